@@ -0,0 +1,108 @@
+package local
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	cferr "github.com/cloudflare/cfssl/errors"
+)
+
+// CRLNumberStore persists the monotonically increasing CRL number
+// that RFC 5280 section 5.2.3 requires a CA to include in every CRL
+// it issues. Implementations must make Next safe to call once per
+// issued CRL, even across process restarts.
+type CRLNumberStore interface {
+	// Current returns the CRL number of the most recently issued
+	// CRL, or nil if none has been issued yet.
+	Current() (*big.Int, error)
+	// Next allocates, persists and returns the CRL number to use for
+	// the CRL about to be issued.
+	Next() (*big.Int, error)
+}
+
+// memoryCRLNumberStore is the CRLNumberStore a Signer falls back to
+// when none has been set with SetCRLNumberStore. The counter lives
+// only in memory, so it does not survive a process restart; callers
+// that issue CRLs across restarts should supply their own persisted
+// store.
+type memoryCRLNumberStore struct {
+	current *big.Int
+}
+
+func (m *memoryCRLNumberStore) Current() (*big.Int, error) {
+	return m.current, nil
+}
+
+func (m *memoryCRLNumberStore) Next() (*big.Int, error) {
+	if m.current == nil {
+		m.current = big.NewInt(0)
+	}
+	m.current = new(big.Int).Add(m.current, big.NewInt(1))
+	return m.current, nil
+}
+
+// SetCRLNumberStore installs the CRLNumberStore used by CRL and
+// AppendCRL to number the CRLs they issue. If it is never called, the
+// signer falls back to an in-memory counter.
+func (s *Signer) SetCRLNumberStore(store CRLNumberStore) {
+	s.crlNumberStore = store
+}
+
+func (s *Signer) crlStore() CRLNumberStore {
+	if s.crlNumberStore == nil {
+		s.crlNumberStore = &memoryCRLNumberStore{}
+	}
+	return s.crlNumberStore
+}
+
+// CRL issues a PEM-encoded X.509 v2 CRL covering revoked, valid from
+// now until expiry has elapsed, signed with the Signer's CA key and
+// certificate.
+func (s *Signer) CRL(revoked []pkix.RevokedCertificate, expiry time.Duration) ([]byte, error) {
+	return s.signCRL(revoked, expiry)
+}
+
+// AppendCRL parses prev, a PEM-encoded CRL previously issued by this
+// Signer, and reissues it with newRevoked appended to its list of
+// revoked certificates.
+func (s *Signer) AppendCRL(prev []byte, newRevoked []pkix.RevokedCertificate, expiry time.Duration) ([]byte, error) {
+	block, _ := pem.Decode(prev)
+	if block == nil {
+		return nil, cferr.New(cferr.CertificateError, cferr.DecodeFailed)
+	}
+
+	prevList, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return nil, cferr.Wrap(cferr.CertificateError, cferr.ParseFailed, err)
+	}
+
+	revoked := append(prevList.RevokedCertificates, newRevoked...)
+	return s.signCRL(revoked, expiry)
+}
+
+func (s *Signer) signCRL(revoked []pkix.RevokedCertificate, expiry time.Duration) ([]byte, error) {
+	number, err := s.crlStore().Next()
+	if err != nil {
+		return nil, cferr.Wrap(cferr.CertificateError, cferr.Unknown, err)
+	}
+
+	now := time.Now()
+	template := &x509.RevocationList{
+		SignatureAlgorithm:  s.sigAlgo,
+		RevokedCertificates: revoked,
+		Number:              number,
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(expiry),
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, s.ca, s.priv)
+	if err != nil {
+		return nil, cferr.Wrap(cferr.CertificateError, cferr.Unknown, err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), nil
+}
@@ -0,0 +1,147 @@
+package local
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCA builds a minimal self-signed CA certificate and key,
+// entirely with the standard library, for use as a Signer's ca/priv
+// in tests that don't need a full config.Signing policy.
+func newTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              time.Unix(0, 0).Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	return ca, key
+}
+
+func TestSignerCRLAndAppendCRL(t *testing.T) {
+	ca, key := newTestCA(t)
+	s := &Signer{ca: ca, priv: key, sigAlgo: x509.ECDSAWithSHA256}
+
+	revoked := []pkix.RevokedCertificate{
+		{SerialNumber: big.NewInt(100), RevocationTime: time.Unix(0, 0)},
+	}
+
+	crlPEM, err := s.CRL(revoked, time.Hour)
+	if err != nil {
+		t.Fatalf("CRL: %v", err)
+	}
+
+	block, _ := pem.Decode(crlPEM)
+	if block == nil || block.Type != "X509 CRL" {
+		t.Fatalf("CRL did not produce a PEM-encoded X509 CRL, got %q", crlPEM)
+	}
+
+	list, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseRevocationList: %v", err)
+	}
+	if list.Number.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("first CRL Number = %v, want 1", list.Number)
+	}
+	if len(list.RevokedCertificates) != 1 || list.RevokedCertificates[0].SerialNumber.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("RevokedCertificates = %v, want one entry with serial 100", list.RevokedCertificates)
+	}
+	if err := list.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("CRL signature does not verify against the CA: %v", err)
+	}
+
+	newRevoked := []pkix.RevokedCertificate{
+		{SerialNumber: big.NewInt(200), RevocationTime: time.Unix(0, 0)},
+	}
+	appendedPEM, err := s.AppendCRL(crlPEM, newRevoked, time.Hour)
+	if err != nil {
+		t.Fatalf("AppendCRL: %v", err)
+	}
+
+	block, _ = pem.Decode(appendedPEM)
+	if block == nil {
+		t.Fatalf("AppendCRL did not produce a PEM block")
+	}
+	list, err = x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseRevocationList (appended): %v", err)
+	}
+	if list.Number.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("appended CRL Number = %v, want 2", list.Number)
+	}
+	if len(list.RevokedCertificates) != 2 {
+		t.Errorf("appended RevokedCertificates has %d entries, want 2", len(list.RevokedCertificates))
+	}
+}
+
+func TestMemoryCRLNumberStoreCurrent(t *testing.T) {
+	store := &memoryCRLNumberStore{}
+
+	current, err := store.Current()
+	if err != nil {
+		t.Fatalf("Current() returned error: %v", err)
+	}
+	if current != nil {
+		t.Errorf("Current() before any Next() = %v, want nil", current)
+	}
+
+	first, err := store.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+	if first.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("first Next() = %v, want 1", first)
+	}
+
+	current, err = store.Current()
+	if err != nil {
+		t.Fatalf("Current() returned error: %v", err)
+	}
+	if current.Cmp(first) != 0 {
+		t.Errorf("Current() after Next() = %v, want %v", current, first)
+	}
+}
+
+func TestMemoryCRLNumberStoreNextMonotonic(t *testing.T) {
+	store := &memoryCRLNumberStore{}
+
+	var prev *big.Int
+	for i := 0; i < 3; i++ {
+		next, err := store.Next()
+		if err != nil {
+			t.Fatalf("Next() returned error: %v", err)
+		}
+		if prev != nil && next.Cmp(prev) <= 0 {
+			t.Fatalf("Next() = %v, want strictly greater than previous %v", next, prev)
+		}
+		prev = next
+	}
+}
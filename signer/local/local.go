@@ -10,10 +10,14 @@ import (
 	"errors"
 	"io/ioutil"
 	"net"
+	"net/mail"
+	"net/url"
 
 	"github.com/cloudflare/cfssl/config"
+	"github.com/cloudflare/cfssl/csr"
 	cferr "github.com/cloudflare/cfssl/errors"
 	"github.com/cloudflare/cfssl/helpers"
+	"github.com/cloudflare/cfssl/initca"
 	"github.com/cloudflare/cfssl/log"
 	"github.com/cloudflare/cfssl/signer"
 )
@@ -21,10 +25,11 @@ import (
 // Signer contains a signer that uses the standard library to
 // support both ECDSA and RSA CA keys.
 type Signer struct {
-	ca      *x509.Certificate
-	priv    crypto.Signer
-	policy  *config.Signing
-	sigAlgo x509.SignatureAlgorithm
+	ca             *x509.Certificate
+	priv           crypto.Signer
+	policy         *config.Signing
+	sigAlgo        x509.SignatureAlgorithm
+	crlNumberStore CRLNumberStore
 }
 
 // NewSigner creates a new Signer directly from a
@@ -48,6 +53,41 @@ func NewSigner(priv crypto.Signer, cert *x509.Certificate, sigAlgo x509.Signatur
 	}, nil
 }
 
+// NewRootSigner creates a new Signer whose ca is a fresh self-signed
+// root certificate built from req and signed by priv, removing the
+// need to call initca separately and feed its PEM output back into
+// NewSigner. A Signer built with NewSigner can still be constructed
+// with a nil cert, in which case sign's s.ca == nil branch
+// self-initializes the root on first use.
+func NewRootSigner(priv crypto.Signer, req *csr.CertificateRequest, policy *config.Signing) (*Signer, error) {
+	if policy == nil {
+		policy = &config.Signing{
+			Profiles: map[string]*config.SigningProfile{},
+			Default:  config.DefaultConfig()}
+	}
+
+	if !policy.Valid() {
+		return nil, cferr.New(cferr.PolicyError, cferr.InvalidPolicy)
+	}
+
+	root, _, err := initca.NewFromSigner(req, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := helpers.ParseCertificatePEM(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		ca:      ca,
+		priv:    priv,
+		sigAlgo: signer.DefaultSigAlgo(priv),
+		policy:  policy,
+	}, nil
+}
+
 // NewSignerFromFile generates a new local signer from a caFile
 // and a caKey file, both PEM encoded.
 func NewSignerFromFile(caFile, caKeyFile string, policy *config.Signing) (*Signer, error) {
@@ -76,12 +116,55 @@ func NewSignerFromFile(caFile, caKeyFile string, policy *config.Signing) (*Signe
 	return NewSigner(priv, parsedCa, signer.DefaultSigAlgo(priv), policy)
 }
 
-func (s *Signer) sign(template *x509.Certificate, profile *config.SigningProfile, serialSeq string) (cert []byte, err error) {
+// sign fills out template from profile and the signer's policy, then
+// signs it with the CA key. If preserveUsage is true (Renew's case),
+// template's KeyUsage, ExtKeyUsage, IsCA and MaxPathLen are narrowed
+// back down to whatever the template carried before FillTemplate ran,
+// so that Renew can preserve a certificate's prior usage but can
+// never exceed what the named profile authorizes.
+func (s *Signer) sign(template *x509.Certificate, profile *config.SigningProfile, serialSeq string, preserveUsage bool) (cert []byte, err error) {
+	// FillTemplate overwrites CRLDistributionPoints, OCSPServer and
+	// IssuingCertificateURL with the profile's values, so preserve
+	// whatever the request already carried and merge the two sets
+	// back in below.
+	reqCRLDistributionPoints := template.CRLDistributionPoints
+	reqOCSPServer := template.OCSPServer
+	reqIssuingCertificateURL := template.IssuingCertificateURL
+
+	var reqKeyUsage x509.KeyUsage
+	var reqExtKeyUsage []x509.ExtKeyUsage
+	var reqIsCA bool
+	var reqMaxPathLen int
+	var reqMaxPathLenZero bool
+	if preserveUsage {
+		reqKeyUsage = template.KeyUsage
+		reqExtKeyUsage = template.ExtKeyUsage
+		reqIsCA = template.IsCA
+		reqMaxPathLen = template.MaxPathLen
+		reqMaxPathLenZero = template.MaxPathLenZero
+	}
+
 	err = signer.FillTemplate(template, s.policy.Default, profile, serialSeq)
 	if err != nil {
 		return
 	}
 
+	template.CRLDistributionPoints = unionStrings(reqCRLDistributionPoints, template.CRLDistributionPoints)
+	template.OCSPServer = unionStrings(reqOCSPServer, template.OCSPServer)
+	template.IssuingCertificateURL = unionStrings(reqIssuingCertificateURL, template.IssuingCertificateURL)
+
+	if preserveUsage {
+		// template now holds whatever profile authorizes; narrow it
+		// down to what the request carried so Renew preserves the
+		// prior certificate's usage without ever widening past the
+		// profile's grant.
+		template.KeyUsage &= reqKeyUsage
+		template.ExtKeyUsage = intersectExtKeyUsage(template.ExtKeyUsage, reqExtKeyUsage)
+		template.IsCA, template.MaxPathLen, template.MaxPathLenZero = narrowCAConstraint(
+			template.IsCA, template.MaxPathLen, template.MaxPathLenZero,
+			reqIsCA, reqMaxPathLen, reqMaxPathLenZero)
+	}
+
 	serialNumber := template.SerialNumber
 	var initRoot bool
 	if s.ca == nil {
@@ -90,12 +173,18 @@ func (s *Signer) sign(template *x509.Certificate, profile *config.SigningProfile
 			return
 		}
 		template.DNSNames = nil
+		template.EmailAddresses = nil
+		template.URIs = nil
 		s.ca = template
 		initRoot = true
 		template.MaxPathLen = signer.MaxPathLen
 	} else if template.IsCA {
-		template.MaxPathLen = 1
+		if !preserveUsage {
+			template.MaxPathLen = 1
+		}
 		template.DNSNames = nil
+		template.EmailAddresses = nil
+		template.URIs = nil
 	}
 
 	derBytes, err := x509.CreateCertificate(rand.Reader, template, s.ca, template.PublicKey, s.priv)
@@ -122,6 +211,93 @@ func replaceSliceIfEmpty(replaced, newContents *[]string) {
 	}
 }
 
+// unionStrings returns the de-duplicated union of a and b, preserving
+// the order in which each value is first seen. A nil result is
+// returned if both inputs are empty, so callers don't end up setting
+// an empty-but-non-nil slice on a certificate template.
+func unionStrings(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(a)+len(b))
+	union := make([]string, 0, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, v := range list {
+			if !seen[v] {
+				seen[v] = true
+				union = append(union, v)
+			}
+		}
+	}
+	return union
+}
+
+// narrowCAConstraint reconciles the CA constraint the profile just
+// authorized (profileIsCA, profileMaxPathLen, profileMaxPathLenZero)
+// with the one the request carried (reqIsCA, reqMaxPathLen,
+// reqMaxPathLenZero). The result can never claim CA status the
+// profile didn't grant, and its path length is never looser than
+// either side's. Per the x509.Certificate contract, MaxPathLen == 0
+// with MaxPathLenZero == false means "no limit" rather than a limit
+// of zero, so that case is treated as unbounded, not as the
+// narrowest option.
+func narrowCAConstraint(profileIsCA bool, profileMaxPathLen int, profileMaxPathLenZero bool, reqIsCA bool, reqMaxPathLen int, reqMaxPathLenZero bool) (isCA bool, maxPathLen int, maxPathLenZero bool) {
+	isCA = profileIsCA && reqIsCA
+	if !isCA {
+		return false, profileMaxPathLen, profileMaxPathLenZero
+	}
+
+	const unbounded = -1
+	effective := func(n int, zero bool) int {
+		if n == 0 && !zero {
+			return unbounded
+		}
+		return n
+	}
+
+	profileLen := effective(profileMaxPathLen, profileMaxPathLenZero)
+	reqLen := effective(reqMaxPathLen, reqMaxPathLenZero)
+
+	narrowest := profileLen
+	switch {
+	case profileLen == unbounded:
+		narrowest = reqLen
+	case reqLen == unbounded:
+		narrowest = profileLen
+	case reqLen < profileLen:
+		narrowest = reqLen
+	}
+
+	if narrowest == unbounded {
+		return isCA, 0, false
+	}
+	return isCA, narrowest, narrowest == 0
+}
+
+// intersectExtKeyUsage returns the values common to both profileEKU
+// and reqEKU, in profileEKU's order, or nil if either is empty. It is
+// used to narrow a renewed certificate's extended key usages down to
+// whatever both the prior certificate and the signing profile permit.
+func intersectExtKeyUsage(profileEKU, reqEKU []x509.ExtKeyUsage) []x509.ExtKeyUsage {
+	if len(profileEKU) == 0 || len(reqEKU) == 0 {
+		return nil
+	}
+
+	allowed := make(map[x509.ExtKeyUsage]bool, len(reqEKU))
+	for _, eku := range reqEKU {
+		allowed[eku] = true
+	}
+
+	var out []x509.ExtKeyUsage
+	for _, eku := range profileEKU {
+		if allowed[eku] {
+			out = append(out, eku)
+		}
+	}
+	return out
+}
+
 func whitelistString(keep bool, field *string) {
 	if !keep {
 		*field = ""
@@ -180,17 +356,26 @@ func PopulateSubjectFromCSR(s *signer.Subject, req pkix.Name) pkix.Name {
 	return name
 }
 
-// OverrideHosts fills template's IPAddresses and DNSNames with the
-// content of hosts, if it is not nil.
+// OverrideHosts fills template's IPAddresses, EmailAddresses, URIs
+// and DNSNames with the content of hosts, if it is not nil. Each
+// entry in hosts is classified in turn as an IP address, an RFC 5322
+// email address, or an RFC 3986 URI (one with an explicit scheme);
+// anything else is treated as a DNS name.
 func OverrideHosts(template *x509.Certificate, hosts []string) {
 	if hosts != nil {
 		template.IPAddresses = []net.IP{}
+		template.EmailAddresses = []string{}
+		template.URIs = []*url.URL{}
 		template.DNSNames = []string{}
 	}
 
 	for i := range hosts {
 		if ip := net.ParseIP(hosts[i]); ip != nil {
 			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if email, err := mail.ParseAddress(hosts[i]); err == nil {
+			template.EmailAddresses = append(template.EmailAddresses, email.Address)
+		} else if uri, err := url.Parse(hosts[i]); err == nil && uri.Scheme != "" {
+			template.URIs = append(template.URIs, uri)
 		} else {
 			template.DNSNames = append(template.DNSNames, hosts[i])
 		}
@@ -227,10 +412,30 @@ func (s *Signer) Sign(req signer.SignRequest) (cert []byte, err error) {
 		return nil, err
 	}
 
+	if req.Hosts == nil {
+		// signer.ParseCertificateRequest only carries the CSR's
+		// DNSNames and IPAddresses through to the template; preserve
+		// its email and URI SANs too, so a CSR-embedded S/MIME or
+		// SPIFFE identity isn't silently dropped when the caller
+		// isn't overriding hosts explicitly via OverrideHosts.
+		csrObject, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return nil, cferr.Wrap(cferr.CertificateError, cferr.ParseFailed, err)
+		}
+		template.EmailAddresses = csrObject.EmailAddresses
+		template.URIs = csrObject.URIs
+	}
+
 	OverrideHosts(template, req.Hosts)
 	template.Subject = PopulateSubjectFromCSR(req.Subject, template.Subject)
 
-	return s.sign(template, profile, serialSeq)
+	// Let the request inject its own revocation/AIA infrastructure
+	// URLs; sign merges these with whatever the profile also sets.
+	template.CRLDistributionPoints = req.CRLDistributionPoints
+	template.OCSPServer = req.OCSPServer
+	template.IssuingCertificateURL = req.IssuingCertificateURL
+
+	return s.sign(template, profile, serialSeq, false)
 }
 
 // SigAlgo returns the RSA signer's signature algorithm.
@@ -0,0 +1,144 @@
+package local
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestNarrowCAConstraint(t *testing.T) {
+	cases := []struct {
+		name                  string
+		profileIsCA           bool
+		profileMaxPathLen     int
+		profileMaxPathLenZero bool
+		reqIsCA               bool
+		reqMaxPathLen         int
+		reqMaxPathLenZero     bool
+		wantIsCA              bool
+		wantMaxPathLen        int
+		wantMaxPathLenZero    bool
+	}{
+		{"profile forbids CA even if prev was a CA", false, 5, false, true, 2, false, false, 5, false},
+		{"prev wasn't a CA even if profile allows it", true, 5, false, false, 0, false, false, 5, false},
+		{"both agree, prev's tighter path len wins", true, 5, false, true, 2, false, true, 2, false},
+		{"both agree, profile's tighter path len wins", true, 2, false, true, 5, false, true, 2, false},
+		{"prev's explicit zero path len is preserved, not treated as unlimited", true, 5, false, true, 0, true, true, 0, true},
+		{"profile's unlimited path len defers to prev's explicit zero", true, 0, false, true, 0, true, true, 0, true},
+		{"prev's unlimited path len defers to profile's tighter limit", true, 2, false, true, 0, false, true, 2, false},
+		{"both unlimited stays unlimited", true, 0, false, true, 0, false, true, 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gotIsCA, gotMaxPathLen, gotMaxPathLenZero := narrowCAConstraint(
+				c.profileIsCA, c.profileMaxPathLen, c.profileMaxPathLenZero,
+				c.reqIsCA, c.reqMaxPathLen, c.reqMaxPathLenZero)
+			if gotIsCA != c.wantIsCA || gotMaxPathLen != c.wantMaxPathLen || gotMaxPathLenZero != c.wantMaxPathLenZero {
+				t.Errorf("narrowCAConstraint(%v, %v, %v, %v, %v, %v) = (%v, %v, %v), want (%v, %v, %v)",
+					c.profileIsCA, c.profileMaxPathLen, c.profileMaxPathLenZero,
+					c.reqIsCA, c.reqMaxPathLen, c.reqMaxPathLenZero,
+					gotIsCA, gotMaxPathLen, gotMaxPathLenZero,
+					c.wantIsCA, c.wantMaxPathLen, c.wantMaxPathLenZero)
+			}
+		})
+	}
+}
+
+func TestIntersectExtKeyUsage(t *testing.T) {
+	cases := []struct {
+		name               string
+		profileEKU, reqEKU []x509.ExtKeyUsage
+		want               []x509.ExtKeyUsage
+	}{
+		{"profile empty", nil, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, nil},
+		{"request empty", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, nil, nil},
+		{
+			"keeps only what both permit",
+			[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			[]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageCodeSigning},
+			[]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := intersectExtKeyUsage(c.profileEKU, c.reqEKU)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("intersectExtKeyUsage(%v, %v) = %v, want %v", c.profileEKU, c.reqEKU, got, c.want)
+			}
+		})
+	}
+}
+
+func TestOverrideHosts(t *testing.T) {
+	hosts := []string{
+		"127.0.0.1",
+		"::1",
+		"user@example.com",
+		"spiffe://example.com/workload",
+		"www.example.com",
+	}
+
+	template := &x509.Certificate{}
+	OverrideHosts(template, hosts)
+
+	wantIPs := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+	if len(template.IPAddresses) != len(wantIPs) {
+		t.Fatalf("IPAddresses = %v, want %v", template.IPAddresses, wantIPs)
+	}
+	for i, ip := range wantIPs {
+		if !template.IPAddresses[i].Equal(ip) {
+			t.Errorf("IPAddresses[%d] = %v, want %v", i, template.IPAddresses[i], ip)
+		}
+	}
+
+	wantEmails := []string{"user@example.com"}
+	if !reflect.DeepEqual(template.EmailAddresses, wantEmails) {
+		t.Errorf("EmailAddresses = %v, want %v", template.EmailAddresses, wantEmails)
+	}
+
+	wantURI, _ := url.Parse("spiffe://example.com/workload")
+	if len(template.URIs) != 1 || template.URIs[0].String() != wantURI.String() {
+		t.Errorf("URIs = %v, want [%v]", template.URIs, wantURI)
+	}
+
+	wantDNS := []string{"www.example.com"}
+	if !reflect.DeepEqual(template.DNSNames, wantDNS) {
+		t.Errorf("DNSNames = %v, want %v", template.DNSNames, wantDNS)
+	}
+}
+
+func TestOverrideHostsNilLeavesTemplateUntouched(t *testing.T) {
+	template := &x509.Certificate{DNSNames: []string{"keep.example.com"}}
+	OverrideHosts(template, nil)
+
+	want := []string{"keep.example.com"}
+	if !reflect.DeepEqual(template.DNSNames, want) {
+		t.Errorf("DNSNames = %v, want %v", template.DNSNames, want)
+	}
+}
+
+func TestUnionStrings(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{"both empty", nil, nil, nil},
+		{"dedupes across both", []string{"a", "b"}, []string{"b", "c"}, []string{"a", "b", "c"}},
+		{"a empty", nil, []string{"x"}, []string{"x"}},
+		{"b empty", []string{"x"}, nil, []string{"x"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := unionStrings(c.a, c.b)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("unionStrings(%v, %v) = %v, want %v", c.a, c.b, got, c.want)
+			}
+		})
+	}
+}
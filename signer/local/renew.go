@@ -0,0 +1,71 @@
+package local
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+)
+
+// renewalCopiedExtensions are the extension OIDs that Renew's
+// template already carries via typed x509.Certificate fields (SANs,
+// key usage, basic constraints, subject key identifier, ...), either
+// because CreateCertificate regenerates them or because Renew copies
+// them explicitly (SubjectKeyId). They must not also be carried
+// forward via ExtraExtensions: CreateCertificate lets ExtraExtensions
+// replace the extensions it builds from typed fields, so the prior
+// certificate's encodings would win over the renewed values.
+var renewalCopiedExtensions = map[string]bool{
+	"2.5.29.14":         true, // Subject Key Identifier
+	"2.5.29.15":         true, // Key Usage
+	"2.5.29.17":         true, // Subject Alternative Name
+	"2.5.29.19":         true, // Basic Constraints
+	"2.5.29.31":         true, // CRL Distribution Points
+	"2.5.29.35":         true, // Authority Key Identifier
+	"2.5.29.37":         true, // Extended Key Usage
+	"1.3.6.1.5.5.7.1.1": true, // Authority Information Access
+}
+
+// Renew builds a new certificate for newKey using prev as a template:
+// its Subject, SANs, KeyUsage, ExtKeyUsage and any extension not
+// already covered by those typed fields are carried forward, a fresh
+// serial number and validity window are assigned from the named
+// profile, and the result is signed with the CA. KeyUsage, ExtKeyUsage
+// and the CA constraint are kept only as far as the profile also
+// grants them. Pass prev.PublicKey as newKey to renew without rotating
+// the key.
+func (s *Signer) Renew(prev *x509.Certificate, newKey crypto.PublicKey, profileName string) ([]byte, error) {
+	profile := s.policy.Profiles[profileName]
+	if profile == nil {
+		profile = s.policy.Default
+	}
+
+	var extra []pkix.Extension
+	for _, ext := range prev.Extensions {
+		if renewalCopiedExtensions[ext.Id.String()] {
+			continue
+		}
+		extra = append(extra, ext)
+	}
+
+	template := &x509.Certificate{
+		Subject:               prev.Subject,
+		DNSNames:              prev.DNSNames,
+		IPAddresses:           prev.IPAddresses,
+		EmailAddresses:        prev.EmailAddresses,
+		URIs:                  prev.URIs,
+		KeyUsage:              prev.KeyUsage,
+		ExtKeyUsage:           prev.ExtKeyUsage,
+		UnknownExtKeyUsage:    prev.UnknownExtKeyUsage,
+		IsCA:                  prev.IsCA,
+		MaxPathLen:            prev.MaxPathLen,
+		MaxPathLenZero:        prev.MaxPathLenZero,
+		SubjectKeyId:          prev.SubjectKeyId,
+		CRLDistributionPoints: prev.CRLDistributionPoints,
+		OCSPServer:            prev.OCSPServer,
+		IssuingCertificateURL: prev.IssuingCertificateURL,
+		ExtraExtensions:       extra,
+		PublicKey:             newKey,
+	}
+
+	return s.sign(template, profile, "", true)
+}
@@ -0,0 +1,109 @@
+package local
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/cfssl/config"
+	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/signer"
+)
+
+func TestSignerRenew(t *testing.T) {
+	ca, caKey := newTestCA(t)
+
+	policy := &config.Signing{
+		Profiles: map[string]*config.SigningProfile{},
+		Default: &config.SigningProfile{
+			Usage:  []string{"signing", "key encipherment", "server auth"},
+			Expiry: time.Hour,
+		},
+	}
+	s := &Signer{ca: ca, priv: caKey, sigAlgo: x509.ECDSAWithSHA256, policy: policy}
+
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	ski := []byte{0xde, 0xad, 0xbe, 0xef}
+	prev := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "renew.example.com"},
+		DNSNames:     []string{"renew.example.com"},
+		SerialNumber: big.NewInt(1),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		SubjectKeyId: ski,
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(time.Hour),
+	}
+
+	certPEM, err := s.Renew(prev, &newKey.PublicKey, "")
+	if err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatalf("Renew did not return a PEM-encoded certificate")
+	}
+	renewed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	if renewed.Subject.CommonName != prev.Subject.CommonName {
+		t.Errorf("Subject.CommonName = %q, want %q", renewed.Subject.CommonName, prev.Subject.CommonName)
+	}
+	if len(renewed.DNSNames) != 1 || renewed.DNSNames[0] != "renew.example.com" {
+		t.Errorf("DNSNames = %v, want [renew.example.com]", renewed.DNSNames)
+	}
+	if !bytes.Equal(renewed.SubjectKeyId, ski) {
+		t.Errorf("SubjectKeyId = %x, want %x", renewed.SubjectKeyId, ski)
+	}
+	if renewed.IsCA {
+		t.Errorf("renewed leaf certificate should not be a CA")
+	}
+	if !renewed.PublicKey.(*ecdsa.PublicKey).Equal(&newKey.PublicKey) {
+		t.Errorf("renewed certificate's public key does not match newKey")
+	}
+}
+
+func TestNewRootSigner(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	req := &csr.CertificateRequest{
+		CN: "Test Root CA",
+		CA: &csr.CAConfig{Expiry: "1h"},
+	}
+
+	s, err := NewRootSigner(priv, req, nil)
+	if err != nil {
+		t.Fatalf("NewRootSigner: %v", err)
+	}
+
+	ca, err := s.Certificate("", "")
+	if err != nil {
+		t.Fatalf("Certificate: %v", err)
+	}
+	if !ca.IsCA {
+		t.Errorf("root certificate is not marked as a CA")
+	}
+	if ca.Subject.CommonName != req.CN {
+		t.Errorf("root certificate CommonName = %q, want %q", ca.Subject.CommonName, req.CN)
+	}
+	if s.SigAlgo() != signer.DefaultSigAlgo(priv) {
+		t.Errorf("SigAlgo() = %v, want %v", s.SigAlgo(), signer.DefaultSigAlgo(priv))
+	}
+}
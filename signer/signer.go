@@ -0,0 +1,71 @@
+// Package signer implements certificate signature functionality for CF-SSL.
+package signer
+
+import "crypto/x509/pkix"
+
+// A Name contains the SubjectInfo fields used to override a
+// certificate's Subject, apart from the common name.
+type Name struct {
+	C  string // Country
+	ST string // State
+	L  string // Locality
+	O  string // OrganisationName
+	OU string // OrganisationalUnitName
+}
+
+// A Whitelist marks which fields of the CSR's subject may be carried
+// through to the signed certificate. A false value drops the CSR's
+// value for that field; it does not stop the SignRequest's Subject
+// from setting that field.
+type Whitelist struct {
+	CN, C, ST, L, O, OU bool
+}
+
+// A Subject contains the information that should be used to override
+// the subject information when signing a certificate.
+type Subject struct {
+	CN        string
+	Names     []Name     `json:"names"`
+	Whitelist *Whitelist `json:"hosts,omitempty"`
+}
+
+// Name returns the PKIX name for the subject.
+func (s *Subject) Name() pkix.Name {
+	var name pkix.Name
+	name.CommonName = s.CN
+
+	for _, n := range s.Names {
+		appendIf(n.C, &name.Country)
+		appendIf(n.ST, &name.Province)
+		appendIf(n.L, &name.Locality)
+		appendIf(n.O, &name.Organization)
+		appendIf(n.OU, &name.OrganizationalUnit)
+	}
+	return name
+}
+
+func appendIf(s string, a *[]string) {
+	if s != "" {
+		*a = append(*a, s)
+	}
+}
+
+// A SignRequest is a certificate signature request, which contains
+// the hostnames to use for the cert, the CSR, optional subject
+// information, and the signature profile.
+//
+// CRLDistributionPoints, OCSPServer and IssuingCertificateURL let a
+// caller inject per-request CRL/AIA URLs; Signer.Sign merges these
+// with whatever the named profile also sets rather than letting one
+// silently overwrite the other.
+type SignRequest struct {
+	Hosts     []string `json:"hosts"`
+	Request   string   `json:"certificate_request"`
+	Subject   *Subject `json:"subject,omitempty"`
+	Profile   string   `json:"profile"`
+	SerialSeq string   `json:"serial_sequence,omitempty"`
+
+	CRLDistributionPoints []string `json:"crl_distribution_points,omitempty"`
+	OCSPServer            []string `json:"ocsp_server,omitempty"`
+	IssuingCertificateURL []string `json:"issuing_certificate_url,omitempty"`
+}